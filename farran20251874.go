@@ -1,267 +1,669 @@
 package main
 
 import (
+	"bufio"
+	"container/heap"
 	"encoding/csv"
+	"flag"
 	"fmt"
 	"io"
 	"log"
-	"math"
 	"os"
+	"runtime"
+	"slices"
 	"strconv"
 	"sync"
 	"time"
+
+	"github.com/Farran-H/Hybrid-Sorting-Alg/hybridsort"
+)
+
+// defaultChunkSize is the number of integers the pipeline reads, sorts,
+// and merges as one unit.
+const defaultChunkSize = 1 << 16
+
+var (
+	keyColumn  = flag.Int("key-column", 0, "zero-based CSV column to sort by")
+	keyType    = flag.String("key-type", "int", "type of the sort key: int, float, string, or time")
+	timeLayout = flag.String("time-layout", time.RFC3339, "Go reference layout used to parse --key-type=time values")
+	order      = flag.String("order", "asc", "sort order: asc or desc")
+	hasHeader  = flag.Bool("header", false, "treat the first row of the input as a header and pass it through unsorted")
+	stable     = flag.Bool("stable", false, "use a stable sort instead of introsort, to preserve the relative order of equal keys")
+
+	chunkSize = flag.Int("chunk-size", defaultChunkSize, "integers per chunk in --pipeline or --external mode")
+	pipeline  = flag.Bool("pipeline", false, "sort a one-int-per-cell CSV with the concurrent read/sort/merge Pipeline instead of the key-aware path (ignores --key-*, --header, and --stable)")
+	external  = flag.Bool("external", false, "sort a one-int-per-cell CSV too large for memory via on-disk chunked ExternalSort instead of the key-aware path (ignores --key-*, --header, and --stable)")
 )
 
 func main() {
-	// Read numbers from a CSV file.
-	numbers, err := readNumbers("in.csv")
+	flag.Parse()
+
+	switch {
+	case *external:
+		runExternalSort(*chunkSize)
+	case *pipeline:
+		runPipeline(*chunkSize)
+	default:
+		runKeyedSort()
+	}
+}
+
+// runExternalSort sorts in.csv with ExternalSort, for one-int-per-cell
+// CSVs too large to load into memory.
+func runExternalSort(chunkSize int) {
+	start := time.Now()
+
+	if err := ExternalSort("in.csv", "out20251874.csv", chunkSize); err != nil {
+		log.Fatalf("Error running external sort: %v", err)
+	}
+
+	elapsed := time.Since(start)
+	fmt.Printf("External-sorted in.csv in %s.\n", elapsed)
+}
+
+// runPipeline sorts in.csv with the concurrent Pipeline, for
+// one-int-per-cell CSVs that fit in memory but benefit from overlapping
+// the read, sort, and merge stages.
+func runPipeline(chunkSize int) {
+	start := time.Now()
+
+	count, sorted, err := Pipeline{ChunkSize: chunkSize}.Run("in.csv", "out20251874.csv")
+	if err != nil {
+		log.Fatalf("Error running pipeline: %v", err)
+	}
+
+	elapsed := time.Since(start)
+	fmt.Printf("Sorted %d numbers in %s.\n", count, elapsed)
+	if sorted {
+		fmt.Println("The numbers are sorted correctly.")
+	} else {
+		fmt.Println("The numbers are not sorted correctly.")
+	}
+}
+
+// runKeyedSort sorts in.csv by an arbitrary key column, using the flags
+// that configure readRecords/keyLess. It is the default mode, since it is
+// the only one that supports --key-column, --key-type, --order,
+// --header, and --stable.
+func runKeyedSort() {
+	header, records, err := readRecords("in.csv", *keyColumn, *keyType, *timeLayout, *hasHeader)
+	if err != nil {
+		log.Fatalf("Error reading records: %v", err)
+	}
+
+	less, err := keyLess(*keyType, *order)
 	if err != nil {
-		log.Fatalf("Error reading numbers: %v", err)
+		log.Fatalf("Error configuring sort: %v", err)
 	}
 
 	// Start timing the sorting process.
 	start := time.Now()
 
-	// Sort the numbers using introsort.
-	introsort(numbers)
+	// introsort is not stable, so --stable falls back to a stable sort.
+	if *stable {
+		slices.SortStableFunc(records, lessToCompare(less))
+	} else {
+		hybridsort.SortSlice(records, less)
+	}
 
 	// Stop timing and calculate elapsed time.
 	elapsed := time.Since(start)
 
-	// Write the sorted numbers to a new CSV file.
-	err = writeNumbers("out20251874.csv", numbers)
-	if err != nil {
-		log.Fatalf("Error writing numbers: %v", err)
+	if err := writeRecords("out20251874.csv", header, records); err != nil {
+		log.Fatalf("Error writing records: %v", err)
 	}
 
-	// Print out the number of sorted numbers and the time taken.
-	fmt.Printf("Sorted %d numbers in %s.\n", len(numbers), elapsed)
+	// Print out the number of sorted records and the time taken.
+	fmt.Printf("Sorted %d numbers in %s.\n", len(records), elapsed)
 
-	// Check if the numbers are sorted correctly and print the result.
-	if isSorted(numbers) {
+	// Check if the records are sorted correctly and print the result.
+	if recordsSorted(records, less) {
 		fmt.Println("The numbers are sorted correctly.")
 	} else {
 		fmt.Println("The numbers are not sorted correctly.")
 	}
 }
 
-// readNumbers reads integers from a CSV file and returns them as a slice.
-func readNumbers(filename string) ([]int, error) {
-	// Open the input CSV file.
+// Record pairs a sort key, extracted from one CSV column, with the
+// original row so the rest of the row's columns survive the sort
+// untouched.
+type Record struct {
+	Key any
+	Row []string
+}
+
+// readRecords reads every row of a CSV file, extracting the sort key for
+// each row from keyColumn according to keyType. If hasHeader is set, the
+// first row is returned separately as header instead of being parsed as
+// a record.
+func readRecords(filename string, keyColumn int, keyType, timeLayout string, hasHeader bool) (header []string, records []Record, err error) {
 	file, err := os.Open(filename)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	defer file.Close()
 
-	// Create a new reader for the CSV file.
-	reader := csv.NewReader(file)
+	reader := csv.NewReader(bufio.NewReader(file))
+
+	if hasHeader {
+		header, err = reader.Read()
+		if err != nil {
+			return nil, nil, err
+		}
+	}
 
-	// Slice to store the numbers.
-	numbers := []int{}
 	for {
-		// Read a record (line) from the CSV file.
-		record, err := reader.Read()
+		row, err := reader.Read()
 		if err == io.EOF {
-			break // End of file reached.
+			break
 		}
 		if err != nil {
-			return nil, err
+			return header, records, err
+		}
+		if keyColumn < 0 || keyColumn >= len(row) {
+			return header, records, fmt.Errorf("row %v has no column %d", row, keyColumn)
 		}
 
-		// Convert each value in the record to an integer and append it to the slice.
-		for _, value := range record {
-			number, err := strconv.Atoi(value)
-			if err != nil {
-				return nil, err
-			}
-			numbers = append(numbers, number)
+		key, err := parseKey(row[keyColumn], keyType, timeLayout)
+		if err != nil {
+			return header, records, err
 		}
+		records = append(records, Record{Key: key, Row: row})
 	}
 
-	return numbers, nil
+	return header, records, nil
 }
 
-// writeNumbers writes a slice of integers to a CSV file.
-func writeNumbers(filename string, numbers []int) error {
-	// Create and open the output CSV file.
+// writeRecords writes header, if non-nil, followed by every record's row
+// to a CSV file.
+func writeRecords(filename string, header []string, records []Record) error {
 	file, err := os.Create(filename)
 	if err != nil {
 		return err
 	}
 	defer file.Close()
 
-	// Create a writer for the CSV file.
-	writer := csv.NewWriter(file)
-	defer writer.Flush()
+	bufOut := bufio.NewWriter(file)
+	writer := csv.NewWriter(bufOut)
 
-	// Write each number in the slice to the CSV file.
-	for _, number := range numbers {
-		err := writer.Write([]string{strconv.Itoa(number)})
-		if err != nil {
+	if header != nil {
+		if err := writer.Write(header); err != nil {
 			return err
 		}
 	}
 
-	return nil
+	rows := make([][]string, len(records))
+	for i, record := range records {
+		rows[i] = record.Row
+	}
+	if err := writer.WriteAll(rows); err != nil {
+		return err
+	}
+
+	return bufOut.Flush()
+}
+
+// parseKey parses value as a sort key of the given keyType. timeLayout is
+// only used when keyType is "time".
+func parseKey(value, keyType, timeLayout string) (any, error) {
+	switch keyType {
+	case "int":
+		return strconv.ParseInt(value, 10, 64)
+	case "float":
+		return strconv.ParseFloat(value, 64)
+	case "string":
+		return value, nil
+	case "time":
+		return time.Parse(timeLayout, value)
+	default:
+		return nil, fmt.Errorf("unknown key type %q", keyType)
+	}
 }
 
-// isSorted checks if a slice of integers is sorted in ascending order.
-func isSorted(numbers []int) bool {
-	for i := 1; i < len(numbers); i++ {
-		if numbers[i-1] > numbers[i] {
-			return false // Found an element out of order.
+// keyLess returns a less function over Record.Key for the given keyType
+// and order ("asc" or "desc").
+func keyLess(keyType, order string) (func(a, b Record) bool, error) {
+	var less func(a, b Record) bool
+	switch keyType {
+	case "int":
+		less = func(a, b Record) bool { return a.Key.(int64) < b.Key.(int64) }
+	case "float":
+		less = func(a, b Record) bool { return a.Key.(float64) < b.Key.(float64) }
+	case "string":
+		less = func(a, b Record) bool { return a.Key.(string) < b.Key.(string) }
+	case "time":
+		less = func(a, b Record) bool { return a.Key.(time.Time).Before(b.Key.(time.Time)) }
+	default:
+		return nil, fmt.Errorf("unknown key type %q", keyType)
+	}
+
+	switch order {
+	case "asc":
+		return less, nil
+	case "desc":
+		return func(a, b Record) bool { return less(b, a) }, nil
+	default:
+		return nil, fmt.Errorf("unknown order %q", order)
+	}
+}
+
+// lessToCompare adapts a less function to the three-way comparator that
+// slices.SortStableFunc expects.
+func lessToCompare(less func(a, b Record) bool) func(a, b Record) int {
+	return func(a, b Record) int {
+		switch {
+		case less(a, b):
+			return -1
+		case less(b, a):
+			return 1
+		default:
+			return 0
 		}
 	}
-	return true
 }
 
-// partition is a helper function for quicksort that partitions the array around a pivot.
-func partition(a []int, low, high int) int {
-	// Use the median of three as the pivot for improved performance.
-	median := medianOfThree(a, low, high)
-	a[median], a[high] = a[high], a[median]
-
-	// Standard partitioning logic.
-	pivot := a[high]
-	i := low - 1
-	for j := low; j < high; j++ {
-		if a[j] < pivot {
-			i++
-			a[i], a[j] = a[j], a[i]
+// recordsSorted checks if records are ordered according to less.
+func recordsSorted(records []Record, less func(a, b Record) bool) bool {
+	for i := 1; i < len(records); i++ {
+		if less(records[i], records[i-1]) {
+			return false // Found a record out of order.
 		}
 	}
-	a[i+1], a[high] = a[high], a[i+1]
-	return i + 1
+	return true
+}
+
+// Pipeline reads, sorts, and writes a CSV of integers with its three
+// stages overlapping instead of running strictly one after another: a
+// reader goroutine streams ChunkSize-sized chunks off disk, a pool of
+// sorter goroutines (one per GOMAXPROCS) sorts each chunk concurrently as
+// it arrives, and a merger stage k-way merges the sorted chunks into the
+// output file. This keeps a large input from sitting idle in RAM while
+// waiting for the read phase to finish before sorting can start.
+type Pipeline struct {
+	// ChunkSize is how many integers each reader/sorter chunk holds.
+	ChunkSize int
 }
 
-// medianOfThree chooses the median of the first, middle, and last elements.
-func medianOfThree(a []int, low, high int) int {
-	mid := low + (high-low)/2
-	if a[mid] < a[low] {
-		a[mid], a[low] = a[low], a[mid]
+// Run executes the pipeline, reading inputPath and writing the sorted
+// result to outputPath. It returns the number of integers written and
+// whether the output turned out to be sorted.
+func (p Pipeline) Run(inputPath, outputPath string) (count int, sorted bool, err error) {
+	rawChunks := make(chan []int, runtime.GOMAXPROCS(0))
+	readErrCh := make(chan error, 1)
+	go func() {
+		defer close(rawChunks)
+		readErrCh <- readChunks(inputPath, p.ChunkSize, rawChunks)
+	}()
+
+	sortedChunks := make(chan []int, runtime.GOMAXPROCS(0))
+	var sorters sync.WaitGroup
+	numSorters := runtime.GOMAXPROCS(0)
+	sorters.Add(numSorters)
+	for i := 0; i < numSorters; i++ {
+		go func() {
+			defer sorters.Done()
+			for chunk := range rawChunks {
+				hybridsort.SortOrdered(chunk)
+				sortedChunks <- chunk
+			}
+		}()
 	}
-	if a[high] < a[low] {
-		a[high], a[low] = a[low], a[high]
+	go func() {
+		sorters.Wait()
+		close(sortedChunks)
+	}()
+
+	// The merger runs as its own stage rather than as a function called
+	// after every chunk has been collected, so it can start folding each
+	// chunk into its merge heap as soon as the sorter pool produces it,
+	// concurrently with the reader and sorters still working.
+	mergeResultCh := make(chan mergeOutcome, 1)
+	go mergeChunkStream(sortedChunks, outputPath, mergeResultCh)
+
+	outcome := <-mergeResultCh
+	if readErr := <-readErrCh; readErr != nil {
+		return 0, false, readErr
 	}
-	if a[mid] < a[high] {
-		a[mid], a[high] = a[high], a[mid]
+	if outcome.err != nil {
+		return 0, false, outcome.err
 	}
-	return high
+	return outcome.count, outcome.sorted, nil
 }
 
-func insertionSort(a []int) {
-	for i := 1; i < len(a); i++ {
-		key := a[i] // The element to be positioned
-		j := i - 1
+// readChunks reads ChunkSize integers at a time from inputPath and sends
+// each chunk on out. The caller is responsible for closing out.
+func readChunks(inputPath string, chunkSize int, out chan<- []int) error {
+	file, err := os.Open(inputPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
 
-		// Move elements that are greater than key to one position ahead of their current position
-		for j >= 0 && a[j] > key {
-			a[j+1] = a[j]
-			j = j - 1
+	reader := csv.NewReader(bufio.NewReader(file))
+	chunk := make([]int, 0, chunkSize)
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
 		}
-		a[j+1] = key // Place key at after the element just smaller than it
+		if err != nil {
+			return err
+		}
+
+		for _, value := range record {
+			number, err := strconv.Atoi(value)
+			if err != nil {
+				return err
+			}
+			chunk = append(chunk, number)
+			if len(chunk) == chunkSize {
+				out <- chunk
+				chunk = make([]int, 0, chunkSize)
+			}
+		}
+	}
+	if len(chunk) > 0 {
+		out <- chunk
 	}
+
+	return nil
 }
 
-// quicksort is an implementation of the QuickSort algorithm with a depth limit for optimization.
-func quicksort(a []int, low, high, depthLimit int) {
-	if low < high {
-		// Use insertion sort for small subarrays for better performance
-		if high-low <= 10 {
-			insertionSort(a[low : high+1])
-			return
+// sliceSource walks an already-sorted in-memory chunk during a merge.
+type sliceSource struct {
+	values []int
+	pos    int
+}
+
+// next returns the chunk's next value. ok is false once the chunk is
+// exhausted.
+func (s *sliceSource) next() (value int, ok bool) {
+	if s.pos >= len(s.values) {
+		return 0, false
+	}
+	value = s.values[s.pos]
+	s.pos++
+	return value, true
+}
+
+// mergeOutcome is what mergeChunkStream reports back over its result
+// channel once it finishes (or fails).
+type mergeOutcome struct {
+	count  int
+	sorted bool
+	err    error
+}
+
+// mergeChunkStream is the pipeline's merger stage. It ingests sorted
+// chunks off sortedChunks as soon as the sorter pool produces them,
+// pushing each onto a min-heap so that work overlaps with reading and
+// sorting. It cannot start emitting rows until sortedChunks is closed,
+// though: chunks are arbitrary slices of the input in read order, not
+// disjoint, increasing value ranges, so nothing is safe to write until
+// every chunk is known and in the heap. Once that happens it streams
+// rows to outputPath one at a time instead of buffering the merged
+// result in memory first.
+func mergeChunkStream(sortedChunks <-chan []int, outputPath string, result chan<- mergeOutcome) {
+	var sources []*sliceSource
+	h := make(mergeHeap, 0)
+	for chunk := range sortedChunks {
+		src := &sliceSource{values: chunk}
+		chunkIndex := len(sources)
+		sources = append(sources, src)
+		if value, ok := src.next(); ok {
+			heap.Push(&h, mergeItem{value: value, chunkIndex: chunkIndex})
+		}
+	}
+
+	out, err := os.Create(outputPath)
+	if err != nil {
+		result <- mergeOutcome{err: err}
+		return
+	}
+	defer out.Close()
+
+	bufOut := bufio.NewWriter(out)
+	writer := csv.NewWriter(bufOut)
+
+	count := 0
+	sorted := true
+	previous := 0
+	row := make([]string, 1)
+	for h.Len() > 0 {
+		smallest := heap.Pop(&h).(mergeItem)
+		if count > 0 && smallest.value < previous {
+			sorted = false
 		}
+		previous = smallest.value
 
-		// Recursively sort the elements before and after partition
-		if depthLimit == 0 {
-			// Switch to heapSortParallel when the depth limit is reached
-			heapSortParallel(a[low : high+1])
+		row[0] = strconv.Itoa(smallest.value)
+		if err := writer.Write(row); err != nil {
+			result <- mergeOutcome{err: err}
 			return
 		}
-		pi := partition(a, low, high)          // Partition the array
-		quicksort(a, low, pi-1, depthLimit-1)  // Sort the elements before the partition
-		quicksort(a, pi+1, high, depthLimit-1) // Sort the elements after the partition
+		count++
+
+		if value, ok := sources[smallest.chunkIndex].next(); ok {
+			heap.Push(&h, mergeItem{value: value, chunkIndex: smallest.chunkIndex})
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		result <- mergeOutcome{err: err}
+		return
+	}
+	if err := bufOut.Flush(); err != nil {
+		result <- mergeOutcome{err: err}
+		return
 	}
+
+	result <- mergeOutcome{count: count, sorted: sorted}
 }
 
-// heapify turns a subtree into a max heap, used in heap sort.
-func heapify(a []int, n, i int) {
-	largest := i // Initialize largest as root
-	l := 2*i + 1 // left child
-	r := 2*i + 2 // right child
+// ExternalSort sorts a CSV file of integers that is too large to load into
+// memory. It streams the input in chunks of chunkSize, sorts each chunk with
+// the hybrid introsort algorithm, spills the sorted chunks to temporary
+// files, and finally merges all of the chunk files into outputPath with a
+// k-way min-heap merge.
+func ExternalSort(inputPath, outputPath string, chunkSize int) error {
+	chunkFiles, err := writeSortedChunks(inputPath, chunkSize)
+	// Always clean up the temp files, whether the merge below succeeds or not.
+	defer func() {
+		for _, name := range chunkFiles {
+			os.Remove(name)
+		}
+	}()
+	if err != nil {
+		return err
+	}
 
-	// If left child is larger than root
-	if l < n && a[l] > a[largest] {
-		largest = l
+	return mergeChunkFiles(chunkFiles, outputPath)
+}
+
+// writeSortedChunks reads chunkSize integers at a time from inputPath, sorts
+// each chunk with introsort, and writes it to its own temporary CSV file. It
+// returns the names of the temp files in the order they were created.
+func writeSortedChunks(inputPath string, chunkSize int) ([]string, error) {
+	file, err := os.Open(inputPath)
+	if err != nil {
+		return nil, err
 	}
-	// If right child is larger than largest so far
-	if r < n && a[r] > a[largest] {
-		largest = r
+	defer file.Close()
+
+	reader := csv.NewReader(bufio.NewReader(file))
+
+	var chunkFiles []string
+	chunk := make([]int, 0, chunkSize)
+
+	flush := func() error {
+		if len(chunk) == 0 {
+			return nil
+		}
+		hybridsort.SortOrdered(chunk)
+		name, err := writeChunkFile(chunk)
+		// writeChunkFile can fail partway through and still have created the
+		// temp file, so record the name before checking err to make sure
+		// ExternalSort's cleanup defer removes it either way.
+		if name != "" {
+			chunkFiles = append(chunkFiles, name)
+		}
+		if err != nil {
+			return err
+		}
+		chunk = make([]int, 0, chunkSize)
+		return nil
 	}
 
-	// If largest is not root, swap and continue heapifying
-	if largest != i {
-		a[i], a[largest] = a[largest], a[i]
-		heapify(a, n, largest)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return chunkFiles, err
+		}
+
+		for _, value := range record {
+			number, err := strconv.Atoi(value)
+			if err != nil {
+				return chunkFiles, err
+			}
+			chunk = append(chunk, number)
+			if len(chunk) == chunkSize {
+				if err := flush(); err != nil {
+					return chunkFiles, err
+				}
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return chunkFiles, err
 	}
+
+	return chunkFiles, nil
 }
 
-// heapifyParallel is the parallel version of the heapify function.
-func heapifyParallel(a []int, n, i int, wg *sync.WaitGroup) {
-	defer wg.Done() // Signal done when the function exits
+// writeChunkFile writes an already-sorted chunk to a new temporary CSV file
+// and returns its path.
+func writeChunkFile(chunk []int) (string, error) {
+	tmp, err := os.CreateTemp("", "hybridsort-chunk-*.csv")
+	if err != nil {
+		return "", err
+	}
+	defer tmp.Close()
+
+	writer := csv.NewWriter(bufio.NewWriter(tmp))
+	for _, number := range chunk {
+		if err := writer.Write([]string{strconv.Itoa(number)}); err != nil {
+			return tmp.Name(), err
+		}
+	}
+	writer.Flush()
+	return tmp.Name(), writer.Error()
+}
 
-	largest := i
-	l := 2*i + 1 // left child
-	r := 2*i + 2 // right child
+// chunkSource is an open chunk file together with the buffered CSV reader
+// used to pull its next value during the merge.
+type chunkSource struct {
+	file   *os.File
+	reader *csv.Reader
+}
 
-	// Same as heapify, but starts new goroutines for recursive calls
-	if l < n && a[l] > a[largest] {
-		largest = l
+// nextValue reads the next integer out of a chunk file. ok is false once the
+// chunk is exhausted.
+func (c *chunkSource) nextValue() (value int, ok bool, err error) {
+	record, err := c.reader.Read()
+	if err == io.EOF {
+		return 0, false, nil
 	}
-	if r < n && a[r] > a[largest] {
-		largest = r
+	if err != nil {
+		return 0, false, err
 	}
-
-	if largest != i {
-		a[i], a[largest] = a[largest], a[i]
-		wg.Add(1) // Add a new task to the wait group
-		go heapifyParallel(a, n, largest, wg)
+	number, err := strconv.Atoi(record[0])
+	if err != nil {
+		return 0, false, err
 	}
+	return number, true, nil
 }
 
-// heapSortParallel sorts an array using the heap sort algorithm in parallel.
-func heapSortParallel(a []int) {
-	n := len(a)
-	var wg sync.WaitGroup // A WaitGroup waits for a collection of goroutines to finish
-
-	for i := n/2 - 1; i >= 0; i-- {
-		// Use parallel heapify for large subarrays
-		if n > 20 {
-			wg.Add(1)
-			go heapifyParallel(a, n, i, &wg)
-		} else {
-			heapify(a, n, i) // Use standard heapify for smaller subarrays
+// mergeItem is a single min-heap node tracking which chunk an in-flight
+// value came from, so the merge can pull the chunk's next value once the
+// item is popped.
+type mergeItem struct {
+	value      int
+	chunkIndex int
+}
+
+// mergeHeap is a container/heap min-heap of mergeItems ordered by value.
+type mergeHeap []mergeItem
+
+func (h mergeHeap) Len() int            { return len(h) }
+func (h mergeHeap) Less(i, j int) bool  { return h[i].value < h[j].value }
+func (h mergeHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *mergeHeap) Push(x interface{}) { *h = append(*h, x.(mergeItem)) }
+func (h *mergeHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	*h = old[:n-1]
+	return item
+}
+
+// mergeChunkFiles performs a k-way merge of the sorted chunk files into
+// outputPath using a min-heap keyed on the next unread value of each chunk.
+func mergeChunkFiles(chunkFiles []string, outputPath string) error {
+	sources := make([]*chunkSource, len(chunkFiles))
+	for i, name := range chunkFiles {
+		file, err := os.Open(name)
+		if err != nil {
+			return err
 		}
+		defer file.Close()
+		sources[i] = &chunkSource{file: file, reader: csv.NewReader(bufio.NewReader(file))}
+	}
+
+	out, err := os.Create(outputPath)
+	if err != nil {
+		return err
 	}
-	wg.Wait() // Wait for all heapify operations to finish
+	defer out.Close()
 
-	for i := n - 1; i >= 0; i-- {
-		// Move current root to end
-		a[0], a[i] = a[i], a[0]
+	bufOut := bufio.NewWriter(out)
+	writer := csv.NewWriter(bufOut)
+	defer writer.Flush()
 
-		// Call max heapify on the reduced heap
-		if i > 20 {
-			wg.Add(1)
-			go heapifyParallel(a, i, 0, &wg)
-			wg.Wait()
-		} else {
-			heapify(a, i, 0)
+	h := make(mergeHeap, 0, len(sources))
+	for i, src := range sources {
+		value, ok, err := src.nextValue()
+		if err != nil {
+			return err
+		}
+		if ok {
+			h = append(h, mergeItem{value: value, chunkIndex: i})
 		}
 	}
-}
+	heap.Init(&h)
 
-func introsort(a []int) {
-	maxDepth := int(math.Log2(float64(len(a)))) * 2
-	quicksort(a, 0, len(a)-1, maxDepth)
+	for h.Len() > 0 {
+		smallest := heap.Pop(&h).(mergeItem)
+		if err := writer.Write([]string{strconv.Itoa(smallest.value)}); err != nil {
+			return err
+		}
+
+		value, ok, err := sources[smallest.chunkIndex].nextValue()
+		if err != nil {
+			return err
+		}
+		if ok {
+			heap.Push(&h, mergeItem{value: value, chunkIndex: smallest.chunkIndex})
+		}
+	}
+
+	writer.Flush()
+	if err := bufOut.Flush(); err != nil {
+		return err
+	}
+	return writer.Error()
 }
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Farran-H/Hybrid-Sorting-Alg/hybridsort"
+)
+
+// writeCSV writes one value per row to a new file under dir and returns its
+// path.
+func writeCSV(t *testing.T, dir string, rows []string) string {
+	t.Helper()
+	path := filepath.Join(dir, "in.csv")
+	if err := os.WriteFile(path, []byte(strings.Join(rows, "\n")+"\n"), 0o644); err != nil {
+		t.Fatalf("writing %s: %v", path, err)
+	}
+	return path
+}
+
+// readInts reads a one-int-per-row CSV back into a slice.
+func readInts(t *testing.T, path string) []int {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading %s: %v", path, err)
+	}
+	var values []int
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		if line == "" {
+			continue
+		}
+		n, err := strconv.Atoi(line)
+		if err != nil {
+			t.Fatalf("parsing row %q: %v", line, err)
+		}
+		values = append(values, n)
+	}
+	return values
+}
+
+// chunkFilesRemaining counts leftover hybridsort-chunk-*.csv temp files, so
+// tests can confirm ExternalSort cleaned up after itself.
+func chunkFilesRemaining(t *testing.T) int {
+	t.Helper()
+	matches, err := filepath.Glob(filepath.Join(os.TempDir(), "hybridsort-chunk-*.csv"))
+	if err != nil {
+		t.Fatalf("globbing temp dir: %v", err)
+	}
+	return len(matches)
+}
+
+func TestExternalSort(t *testing.T) {
+	dir := t.TempDir()
+	want := []int{9, 1, 8, 2, 7, 3, 6, 4, 5, 0, 9, 1}
+	rows := make([]string, len(want))
+	for i, n := range want {
+		rows[i] = strconv.Itoa(n)
+	}
+	inPath := writeCSV(t, dir, rows)
+	outPath := filepath.Join(dir, "out.csv")
+
+	before := chunkFilesRemaining(t)
+	if err := ExternalSort(inPath, outPath, 3); err != nil {
+		t.Fatalf("ExternalSort: %v", err)
+	}
+
+	got := readInts(t, outPath)
+	wantSorted := append([]int(nil), want...)
+	sort.Ints(wantSorted)
+	if len(got) != len(wantSorted) {
+		t.Fatalf("got %d values, want %d", len(got), len(wantSorted))
+	}
+	for i := range wantSorted {
+		if got[i] != wantSorted[i] {
+			t.Fatalf("mismatch at index %d: got %d, want %d", i, got[i], wantSorted[i])
+		}
+	}
+
+	if after := chunkFilesRemaining(t); after != before {
+		t.Fatalf("ExternalSort left %d chunk files behind", after-before)
+	}
+}
+
+// TestExternalSortCleansUpOnError forces writeSortedChunks to fail partway
+// through (a non-numeric value after a full chunk has already been flushed
+// to a temp file), and checks that ExternalSort's cleanup defer still
+// removes every chunk file it created, not just the ones flush appended
+// before hitting the error.
+func TestExternalSortCleansUpOnError(t *testing.T) {
+	dir := t.TempDir()
+	inPath := writeCSV(t, dir, []string{"1", "2", "3", "not-a-number"})
+	outPath := filepath.Join(dir, "out.csv")
+
+	before := chunkFilesRemaining(t)
+	err := ExternalSort(inPath, outPath, 3)
+	if err == nil {
+		t.Fatal("expected an error from the malformed input, got nil")
+	}
+
+	if after := chunkFilesRemaining(t); after != before {
+		t.Fatalf("ExternalSort left %d chunk files behind after an error", after-before)
+	}
+}
+
+func TestPipelineRun(t *testing.T) {
+	dir := t.TempDir()
+	want := []int{40, 10, 30, 20, 25, 15, 35, 5, 45, 0}
+	rows := make([]string, len(want))
+	for i, n := range want {
+		rows[i] = strconv.Itoa(n)
+	}
+	inPath := writeCSV(t, dir, rows)
+	outPath := filepath.Join(dir, "out.csv")
+
+	count, sorted, err := Pipeline{ChunkSize: 3}.Run(inPath, outPath)
+	if err != nil {
+		t.Fatalf("Pipeline.Run: %v", err)
+	}
+	if count != len(want) {
+		t.Fatalf("count = %d, want %d", count, len(want))
+	}
+	if !sorted {
+		t.Fatal("Run reported sorted = false for a sorted result")
+	}
+
+	got := readInts(t, outPath)
+	wantSorted := append([]int(nil), want...)
+	sort.Ints(wantSorted)
+	for i := range wantSorted {
+		if got[i] != wantSorted[i] {
+			t.Fatalf("mismatch at index %d: got %d, want %d", i, got[i], wantSorted[i])
+		}
+	}
+}
+
+func TestReadWriteRecordsRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	inPath := writeCSV(t, dir, []string{"name,score", "carol,3", "alice,1", "bob,2"})
+
+	header, records, err := readRecords(inPath, 1, "int", time.RFC3339, true)
+	if err != nil {
+		t.Fatalf("readRecords: %v", err)
+	}
+	if len(header) != 2 || header[0] != "name" {
+		t.Fatalf("header = %v, want [name score]", header)
+	}
+	if len(records) != 3 {
+		t.Fatalf("got %d records, want 3", len(records))
+	}
+
+	less, err := keyLess("int", "asc")
+	if err != nil {
+		t.Fatalf("keyLess: %v", err)
+	}
+	sorted := append([]Record(nil), records...)
+	hybridsort.SortSlice(sorted, less)
+	if !recordsSorted(sorted, less) {
+		t.Fatal("records not sorted after SortSlice")
+	}
+	if sorted[0].Row[0] != "alice" {
+		t.Fatalf("first row = %v, want alice first", sorted[0].Row)
+	}
+
+	outPath := filepath.Join(dir, "out.csv")
+	if err := writeRecords(outPath, header, sorted); err != nil {
+		t.Fatalf("writeRecords: %v", err)
+	}
+	data, err := os.ReadFile(outPath)
+	if err != nil {
+		t.Fatalf("reading %s: %v", outPath, err)
+	}
+	want := "name,score\nalice,1\nbob,2\ncarol,3\n"
+	if string(data) != want {
+		t.Fatalf("output = %q, want %q", string(data), want)
+	}
+}
+
+func TestReadRecordsBadColumn(t *testing.T) {
+	dir := t.TempDir()
+	inPath := writeCSV(t, dir, []string{"1,2"})
+
+	if _, _, err := readRecords(inPath, 5, "int", time.RFC3339, false); err == nil {
+		t.Fatal("expected an error for an out-of-range key column, got nil")
+	}
+}
+
+func TestParseKey(t *testing.T) {
+	cases := []struct {
+		keyType string
+		value   string
+		wantErr bool
+	}{
+		{"int", "42", false},
+		{"int", "not-an-int", true},
+		{"float", "3.14", false},
+		{"float", "not-a-float", true},
+		{"string", "anything", false},
+		{"time", "2024-01-02T15:04:05Z", false},
+		{"time", "not-a-time", true},
+		{"bogus", "1", true},
+	}
+
+	for _, c := range cases {
+		_, err := parseKey(c.value, c.keyType, time.RFC3339)
+		if (err != nil) != c.wantErr {
+			t.Errorf("parseKey(%q, %q): err = %v, wantErr %v", c.value, c.keyType, err, c.wantErr)
+		}
+	}
+}
+
+func TestKeyLess(t *testing.T) {
+	low, err := parseKey("1", "int", time.RFC3339)
+	if err != nil {
+		t.Fatalf("parseKey: %v", err)
+	}
+	high, err := parseKey("2", "int", time.RFC3339)
+	if err != nil {
+		t.Fatalf("parseKey: %v", err)
+	}
+	a := Record{Key: low}
+	b := Record{Key: high}
+
+	asc, err := keyLess("int", "asc")
+	if err != nil {
+		t.Fatalf("keyLess asc: %v", err)
+	}
+	if !asc(a, b) || asc(b, a) {
+		t.Fatal("asc order not respected")
+	}
+
+	desc, err := keyLess("int", "desc")
+	if err != nil {
+		t.Fatalf("keyLess desc: %v", err)
+	}
+	if !desc(b, a) || desc(a, b) {
+		t.Fatal("desc order not respected")
+	}
+
+	if _, err := keyLess("int", "sideways"); err == nil {
+		t.Fatal("expected an error for an unknown order, got nil")
+	}
+	if _, err := keyLess("bogus", "asc"); err == nil {
+		t.Fatal("expected an error for an unknown key type, got nil")
+	}
+}
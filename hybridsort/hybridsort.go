@@ -0,0 +1,358 @@
+// Package hybridsort implements the introsort-based hybrid sorting
+// algorithm (quicksort with a pdqsort-style pivot selection, an
+// insertion-sort cutoff for small ranges, and a heapsort fallback once
+// the recursion gets too deep) against the standard sort.Interface, so
+// it works on any type the caller can describe with Len/Less/Swap rather
+// than just []int.
+package hybridsort
+
+import (
+	"cmp"
+	"math"
+	"math/rand"
+	"runtime"
+	"sort"
+	"sync"
+)
+
+const (
+	// insertionSortCutoff is the largest range quicksort hands off to
+	// insertionSort instead of partitioning further.
+	insertionSortCutoff = 10
+
+	// parallelThreshold is the minimum number of elements a partition must
+	// contain on both sides before it is considered for concurrent recursion.
+	// Below this, the goroutine overhead isn't worth it.
+	parallelThreshold = 1 << 14
+
+	// ninetherThreshold is how many consecutive bad partitions (see
+	// isBadPartition) it takes before quicksort switches its pivot
+	// selection from median-of-three to a ninther, which is harder for an
+	// adversarial input to fool.
+	ninetherThreshold = 1
+
+	// randomPivotLimit is how many consecutive bad partitions it takes
+	// before quicksort perturbs the range with a random swap, to break
+	// patterns (like the Musser killer sequence) that repeatedly defeat
+	// deterministic pivot selection.
+	randomPivotLimit = 3
+
+	// sortedRunStreak is how many consecutive no-swap partitions it takes
+	// before quicksort pays for a linear scan to check whether the whole
+	// remaining range is already sorted or reverse-sorted.
+	sortedRunStreak = 2
+)
+
+// Options configures how Sort, SortSlice, and SortOrdered run.
+type Options struct {
+	// Parallel enables partition-level concurrency: once a partition
+	// splits a large enough range in two, the two sides may be sorted on
+	// separate goroutines.
+	Parallel bool
+	// MaxWorkers caps how many partitions may be sorted concurrently. If
+	// <= 0 and Parallel is set, it defaults to runtime.GOMAXPROCS(0).
+	MaxWorkers int
+}
+
+// Sort sorts data in place using the hybrid introsort algorithm. By
+// default it runs serially; pass an Options with Parallel set to allow
+// large partitions to be sorted concurrently.
+func Sort(data sort.Interface, opts ...Options) {
+	var o Options
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+	introsort(data, o)
+}
+
+// sliceAdapter adapts a []T plus a less function to sort.Interface so
+// SortSlice and SortOrdered can reuse the same Sort implementation.
+type sliceAdapter[T any] struct {
+	data []T
+	less func(a, b T) bool
+}
+
+func (s sliceAdapter[T]) Len() int           { return len(s.data) }
+func (s sliceAdapter[T]) Less(i, j int) bool { return s.less(s.data[i], s.data[j]) }
+func (s sliceAdapter[T]) Swap(i, j int)      { s.data[i], s.data[j] = s.data[j], s.data[i] }
+
+// SortSlice sorts s in place using the hybrid introsort algorithm, with
+// ordering determined by less.
+func SortSlice[T any](s []T, less func(a, b T) bool, opts ...Options) {
+	Sort(sliceAdapter[T]{data: s, less: less}, opts...)
+}
+
+// SortOrdered sorts s in place in ascending order using the hybrid
+// introsort algorithm.
+func SortOrdered[T cmp.Ordered](s []T, opts ...Options) {
+	SortSlice(s, func(a, b T) bool { return a < b }, opts...)
+}
+
+// parallelContext is shared by every quicksort call in a single Sort, and
+// bounds how many partitions are being sorted on other goroutines at once.
+type parallelContext struct {
+	sem chan struct{}
+}
+
+// tryAcquire claims a worker slot without blocking. It reports whether the
+// slot was claimed.
+func (pc *parallelContext) tryAcquire() bool {
+	select {
+	case pc.sem <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+func (pc *parallelContext) release() {
+	<-pc.sem
+}
+
+// partition is the Lomuto partition scheme around the pivot already
+// placed at a[high]. It reports the pivot's final index and whether it
+// performed any swap, so the caller can tell a well-balanced range (no
+// swaps needed) from a genuine partition.
+func partition(a sort.Interface, low, high int) (pivotIndex int, swapped bool) {
+	i := low - 1
+	for j := low; j < high; j++ {
+		if a.Less(j, high) {
+			i++
+			if i != j {
+				a.Swap(i, j)
+				swapped = true
+			}
+		}
+	}
+	if i+1 != high {
+		a.Swap(i+1, high)
+		swapped = true
+	}
+	return i + 1, swapped
+}
+
+// medianOfThreeIndex returns whichever of i, j, k holds the median value,
+// without mutating a.
+func medianOfThreeIndex(a sort.Interface, i, j, k int) int {
+	if a.Less(j, i) {
+		if a.Less(k, j) {
+			return j
+		}
+		if a.Less(k, i) {
+			return k
+		}
+		return i
+	}
+	if a.Less(k, j) {
+		if a.Less(k, i) {
+			return i
+		}
+		return k
+	}
+	return j
+}
+
+// ninther picks a pivot candidate as the median of three medians-of-three,
+// sampled across [low, high]. It is pdqsort's defense against inputs
+// (like the Musser killer sequence) crafted to make plain median-of-three
+// repeatedly choose a near-worst-case pivot.
+func ninther(a sort.Interface, low, high int) int {
+	length := high - low + 1
+	step := length / 8
+	if step < 1 {
+		step = 1
+	}
+
+	m1 := medianOfThreeIndex(a, low, low+step, low+2*step)
+	mid := low + length/2
+	m2 := medianOfThreeIndex(a, mid-step, mid, mid+step)
+	m3 := medianOfThreeIndex(a, high-2*step, high-step, high)
+	return medianOfThreeIndex(a, m1, m2, m3)
+}
+
+// insertionSort sorts the range [low, high] of a. It is used directly for
+// small ranges, where its low overhead beats quicksort's.
+func insertionSort(a sort.Interface, low, high int) {
+	for i := low + 1; i <= high; i++ {
+		for j := i; j > low && a.Less(j, j-1); j-- {
+			a.Swap(j, j-1)
+		}
+	}
+}
+
+// scanRun does a single linear pass over [low, high] and reports whether
+// it is already sorted ascending, or sorted descending (so it can be
+// fixed with a single reversal).
+func scanRun(a sort.Interface, low, high int) (ascending, descending bool) {
+	ascending, descending = true, true
+	for i := low; i < high; i++ {
+		if a.Less(i+1, i) {
+			ascending = false
+		}
+		if a.Less(i, i+1) {
+			descending = false
+		}
+		if !ascending && !descending {
+			return false, false
+		}
+	}
+	return ascending, descending
+}
+
+// reverseRange reverses a[low:high+1] in place.
+func reverseRange(a sort.Interface, low, high int) {
+	for i, j := low, high; i < j; i, j = i+1, j-1 {
+		a.Swap(i, j)
+	}
+}
+
+// isBadPartition reports whether a partition split the range so
+// unevenly that the smaller side holds less than an eighth of it, the
+// classic pdqsort signal that the pivot choice is being defeated.
+func isBadPartition(low, high, pivotIndex int) bool {
+	size := high - low + 1
+	smaller := pivotIndex - low
+	if high-pivotIndex < smaller {
+		smaller = high - pivotIndex
+	}
+	return smaller < size/8
+}
+
+// quicksort is an implementation of the QuickSort algorithm with a depth
+// limit for optimization, plus pdqsort-style pattern-defeating behavior:
+// it short-circuits already-sorted or reverse-sorted runs, escalates
+// pivot selection from median-of-three to a ninther after repeated bad
+// partitions, and randomly perturbs the range if that still isn't enough
+// to break an adversarial pattern. badPartitions and sortedStreak carry
+// that state down the recursion. When pc is non-nil and a partition's two
+// sides are both larger than parallelThreshold, it sorts one side on a
+// spare goroutine claimed from pc, bounding total concurrency instead of
+// spawning unbounded goroutines per element like the old heap-based
+// parallel path did.
+func quicksort(a sort.Interface, low, high, depthLimit, badPartitions, sortedStreak int, pc *parallelContext) {
+	if low >= high {
+		return
+	}
+
+	// Use insertion sort for small ranges for better performance.
+	if high-low <= insertionSortCutoff {
+		insertionSort(a, low, high)
+		return
+	}
+
+	// Switch to heapsort when the depth limit is reached.
+	if depthLimit == 0 {
+		heapSort(a, low, high)
+		return
+	}
+
+	// A streak of partitions that needed no swaps is a strong signal the
+	// range is already (reverse-)sorted; pay for one confirming scan.
+	if sortedStreak >= sortedRunStreak {
+		if ascending, descending := scanRun(a, low, high); ascending {
+			return
+		} else if descending {
+			reverseRange(a, low, high)
+			return
+		}
+		sortedStreak = 0
+	}
+
+	// Repeated bad partitions mean the input is fighting the pivot
+	// selection; perturb it before picking the next pivot.
+	if badPartitions >= randomPivotLimit {
+		j := low + 1 + rand.Intn(high-low-1)
+		a.Swap(low+(high-low)/2, j)
+	}
+
+	var pivot int
+	if badPartitions >= ninetherThreshold {
+		pivot = ninther(a, low, high)
+	} else {
+		pivot = medianOfThreeIndex(a, low, low+(high-low)/2, high)
+	}
+	a.Swap(pivot, high)
+
+	pi, swapped := partition(a, low, high) // Partition the range
+
+	nextBad := 0
+	if isBadPartition(low, high, pi) {
+		nextBad = badPartitions + 1
+	}
+	nextSortedStreak := 0
+	if !swapped {
+		nextSortedStreak = sortedStreak + 1
+	}
+
+	if pc != nil && pi-low > parallelThreshold && high-pi > parallelThreshold && pc.tryAcquire() {
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer pc.release()
+			quicksort(a, low, pi-1, depthLimit-1, nextBad, nextSortedStreak, pc)
+		}()
+		quicksort(a, pi+1, high, depthLimit-1, nextBad, nextSortedStreak, pc)
+		wg.Wait()
+		return
+	}
+
+	quicksort(a, low, pi-1, depthLimit-1, nextBad, nextSortedStreak, pc)  // Sort the elements before the partition
+	quicksort(a, pi+1, high, depthLimit-1, nextBad, nextSortedStreak, pc) // Sort the elements after the partition
+}
+
+// heapify turns the subtree rooted at i (within the range starting at low,
+// of size n) into a max heap, used in heap sort.
+func heapify(a sort.Interface, low, n, i int) {
+	largest := i // Initialize largest as root
+	l := 2*i + 1 // left child
+	r := 2*i + 2 // right child
+
+	// If left child is larger than root
+	if l < n && a.Less(low+largest, low+l) {
+		largest = l
+	}
+	// If right child is larger than largest so far
+	if r < n && a.Less(low+largest, low+r) {
+		largest = r
+	}
+
+	// If largest is not root, swap and continue heapifying
+	if largest != i {
+		a.Swap(low+i, low+largest)
+		heapify(a, low, n, largest)
+	}
+}
+
+// heapSort sorts the range [low, high] of a using the heap sort algorithm.
+// It is the depth-limit fallback for quicksort, so it runs serially: it
+// only ever covers one partition's worth of work, and that partition may
+// itself be running alongside siblings under quicksort's own concurrency.
+func heapSort(a sort.Interface, low, high int) {
+	n := high - low + 1
+
+	for i := n/2 - 1; i >= 0; i-- {
+		heapify(a, low, n, i)
+	}
+
+	for i := n - 1; i >= 0; i-- {
+		// Move current root to end
+		a.Swap(low, low+i)
+		heapify(a, low, i, 0)
+	}
+}
+
+func introsort(a sort.Interface, o Options) {
+	maxDepth := int(math.Log2(float64(a.Len()))) * 2
+
+	var pc *parallelContext
+	if o.Parallel {
+		maxWorkers := o.MaxWorkers
+		if maxWorkers <= 0 {
+			maxWorkers = runtime.GOMAXPROCS(0)
+		}
+		pc = &parallelContext{sem: make(chan struct{}, maxWorkers)}
+	}
+
+	quicksort(a, 0, a.Len()-1, maxDepth, 0, 0, pc)
+}
@@ -0,0 +1,76 @@
+package hybridsort
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// randomInts returns n random ints.
+func randomInts(n int) []int {
+	r := rand.New(rand.NewSource(1))
+	data := make([]int, n)
+	for i := range data {
+		data[i] = r.Intn(n)
+	}
+	return data
+}
+
+// sortedInts returns 0..n-1 in ascending order.
+func sortedInts(n int) []int {
+	data := make([]int, n)
+	for i := range data {
+		data[i] = i
+	}
+	return data
+}
+
+// reverseInts returns 0..n-1 in descending order.
+func reverseInts(n int) []int {
+	data := make([]int, n)
+	for i := range data {
+		data[i] = n - i
+	}
+	return data
+}
+
+// killerInts builds a simplified median-of-three killer pattern: an
+// interleaving of ascending and descending runs designed to make plain
+// median-of-three pivot selection repeatedly land on a near-worst-case
+// pivot, the failure mode quicksort's ninther/random-perturbation
+// fallback exists to defeat.
+func killerInts(n int) []int {
+	data := make([]int, n)
+	for i := range data {
+		if i%2 == 0 {
+			data[i] = i
+		} else {
+			data[i] = n - i
+		}
+	}
+	return data
+}
+
+func benchmarkSortOrdered(b *testing.B, gen func(int) []int, n int) {
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		data := gen(n)
+		b.StartTimer()
+		SortOrdered(data)
+	}
+}
+
+const benchSize = 50_000
+
+func BenchmarkSortOrderedRandom(b *testing.B)  { benchmarkSortOrdered(b, randomInts, benchSize) }
+func BenchmarkSortOrderedSorted(b *testing.B)  { benchmarkSortOrdered(b, sortedInts, benchSize) }
+func BenchmarkSortOrderedReverse(b *testing.B) { benchmarkSortOrdered(b, reverseInts, benchSize) }
+func BenchmarkSortOrderedKiller(b *testing.B)  { benchmarkSortOrdered(b, killerInts, benchSize) }
+
+func BenchmarkSortOrderedParallelRandom(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		data := randomInts(benchSize)
+		b.StartTimer()
+		SortOrdered(data, Options{Parallel: true})
+	}
+}
@@ -0,0 +1,87 @@
+package hybridsort
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func TestSortOrdered(t *testing.T) {
+	cases := map[string][]int{
+		"empty":       {},
+		"single":      {42},
+		"random":      randomInts(2000),
+		"sorted":      sortedInts(2000),
+		"reverse":     reverseInts(2000),
+		"duplicates":  repeatInts(2000, 5),
+		"allEqual":    repeatInts(500, 1),
+		"killer":      killerInts(2000),
+		"twoElements": {2, 1},
+	}
+
+	for name, data := range cases {
+		t.Run(name, func(t *testing.T) {
+			want := append([]int(nil), data...)
+			sort.Ints(want)
+
+			got := append([]int(nil), data...)
+			SortOrdered(got)
+
+			assertEqual(t, got, want)
+		})
+	}
+}
+
+func TestSortOrderedParallel(t *testing.T) {
+	data := randomInts(200_000)
+	want := append([]int(nil), data...)
+	sort.Ints(want)
+
+	got := append([]int(nil), data...)
+	SortOrdered(got, Options{Parallel: true, MaxWorkers: 4})
+
+	assertEqual(t, got, want)
+}
+
+func TestSortSlice(t *testing.T) {
+	type pair struct {
+		key, value int
+	}
+
+	data := make([]pair, 500)
+	r := rand.New(rand.NewSource(7))
+	for i := range data {
+		data[i] = pair{key: r.Intn(50), value: i}
+	}
+
+	SortSlice(data, func(a, b pair) bool { return a.key < b.key })
+
+	for i := 1; i < len(data); i++ {
+		if data[i].key < data[i-1].key {
+			t.Fatalf("not sorted at index %d: %+v before %+v", i, data[i-1], data[i])
+		}
+	}
+}
+
+func assertEqual(t *testing.T, got, want []int) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("length mismatch: got %d, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("mismatch at index %d: got %d, want %d", i, got[i], want[i])
+		}
+	}
+}
+
+// repeatInts returns n ints drawn from [0, distinct), so distinct < n
+// forces duplicate keys.
+func repeatInts(n, distinct int) []int {
+	r := rand.New(rand.NewSource(11))
+	data := make([]int, n)
+	for i := range data {
+		data[i] = r.Intn(distinct)
+	}
+	return data
+}